@@ -0,0 +1,140 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Copyright (c) 2015-2020 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdin is the single *bufio.Reader shared by every call site in this
+// program that reads a line from standard input -- password prompts, the
+// '-' stdin argument convention in main, and -batch reading its commands
+// from stdin.  A bufio.Reader fills its internal buffer from an arbitrary
+// number of bytes on its first Read, so constructing a fresh reader per
+// call can silently swallow input meant for a later read; sharing one
+// reader keeps sequential stdin consumers from stepping on each other.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readFirstLine reads and returns the first line of the file at path, with
+// any trailing CR/LF stripped -- the same handling already used for the '-'
+// stdin argument in main.  Unlike that argument, a first line that is empty
+// or all whitespace is treated as an error rather than silently returning
+// an empty password.
+func readFirstLine(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) == "" {
+		return "", errors.New("file is empty")
+	}
+	return line, nil
+}
+
+// promptPassword prompts for a password on an interactive terminal without
+// echoing it, or reads a single line from stdin when it is not a terminal.
+func promptPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		pass, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(pass), nil
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// resolvePasswords applies the -rpcpass-file/-proxypass-file,
+// DCRCTL_RPCPASS/DCRCTL_PROXYPASS, and -rpcpass-stdin/-proxypass-stdin
+// password sources to cfg, in that precedence order, falling back to
+// whatever -P/-rpcpass and -proxypass already set.  It warns on stderr when
+// the command line password is used together with RPCUser on an
+// interactive terminal, since the secure alternatives now exist.
+func resolvePasswords(cfg *config) error {
+	rpcPassFromFlag := cfg.RPCPassword
+	// Only a -P/-rpcpass given on the command line itself, not one set by
+	// the config file, is warned about below.
+	rpcPassOnCmdLine := false
+	for _, a := range os.Args[1:] {
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		name := strings.SplitN(strings.TrimLeft(a, "-"), "=", 2)[0]
+		if name == "P" || name == "rpcpass" {
+			rpcPassOnCmdLine = true
+		}
+	}
+	switch {
+	case cfg.RPCPassFile != "":
+		pass, err := readFirstLine(cfg.RPCPassFile)
+		if err != nil {
+			return fmt.Errorf("-rpcpass-file: %v", err)
+		}
+		cfg.RPCPassword = pass
+
+	case os.Getenv("DCRCTL_RPCPASS") != "":
+		cfg.RPCPassword = os.Getenv("DCRCTL_RPCPASS")
+
+	case cfg.RPCPassStdin:
+		pass, err := promptPassword("RPC password: ")
+		if err != nil {
+			return fmt.Errorf("-rpcpass-stdin: %v", err)
+		}
+		cfg.RPCPassword = pass
+
+	default:
+		if rpcPassOnCmdLine && rpcPassFromFlag != "" && cfg.RPCUser != "" &&
+			term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Fprintln(os.Stderr, "warning: -P/-rpcpass on the command "+
+				"line can leak the password via ps or shell history; "+
+				"consider -rpcpass-file, DCRCTL_RPCPASS, or "+
+				"-rpcpass-stdin instead")
+		}
+	}
+
+	switch {
+	case cfg.ProxyPassFile != "":
+		pass, err := readFirstLine(cfg.ProxyPassFile)
+		if err != nil {
+			return fmt.Errorf("-proxypass-file: %v", err)
+		}
+		cfg.ProxyPass = pass
+
+	case os.Getenv("DCRCTL_PROXYPASS") != "":
+		cfg.ProxyPass = os.Getenv("DCRCTL_PROXYPASS")
+
+	case cfg.ProxyPassStdin:
+		pass, err := promptPassword("Proxy password: ")
+		if err != nil {
+			return fmt.Errorf("-proxypass-stdin: %v", err)
+		}
+		cfg.ProxyPass = pass
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Copyright (c) 2015-2020 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	wallettypes "decred.org/dcrwallet/rpc/jsonrpc/types"
+	"github.com/decred/dcrd/dcrjson/v3"
+	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v2"
+)
+
+// rawCaller is implemented by callers that can send an already-marshalled
+// JSON-RPC payload, such as a batch request array, in a single round trip.
+// It is checked for on a caller, and used, only by -batch mode; most callers
+// (a wsrpc.Client or the agent) don't implement it since they only support
+// one request per call.
+type rawCaller interface {
+	CallRaw(ctx context.Context, payload []byte) ([]json.RawMessage, error)
+}
+
+// batchLine is one line of -batch input, parsed the same way as a single
+// command line invocation.
+type batchLine struct {
+	text   string
+	method string
+	cmd    interface{}
+	err    error
+}
+
+// runBatch implements -batch mode.  Every non-blank line read from in is
+// parsed the same way as a single command line invocation and turned into a
+// dcrjson command.  If client supports sending a raw batch in one round
+// trip (only httpClient, i.e. -http, currently does), all lines that parsed
+// successfully are sent together as a single JSON-RPC 2.0 batch request;
+// otherwise -- the default websocket client and the agent only support one
+// request per call -- each line is sent individually and serially through
+// client.Call.  Results (and any per-line errors) are printed back in input
+// order, and a line's failure doesn't prevent the rest of the batch from
+// being reported.
+func runBatch(ctx context.Context, cfg *config, client caller, in io.Reader, format *resultFormatter) error {
+	lines, err := parseBatch(cfg, in)
+	if err != nil {
+		return err
+	}
+
+	results := make(map[int]json.RawMessage)
+	lineErrs := make(map[int]error)
+
+	if rc, ok := client.(rawCaller); ok {
+		var batch []json.RawMessage
+		for i, ln := range lines {
+			if ln.err != nil {
+				continue
+			}
+			marshalled, err := dcrjson.MarshalCmd("2.0", uint64(i), ln.cmd)
+			if err != nil {
+				lineErrs[i] = err
+				continue
+			}
+			batch = append(batch, marshalled)
+		}
+		if len(batch) > 0 {
+			payload, err := json.Marshal(batch)
+			if err != nil {
+				return err
+			}
+			responses, err := rc.CallRaw(ctx, payload)
+			if err != nil {
+				return err
+			}
+			for _, raw := range responses {
+				var resp struct {
+					Result json.RawMessage   `json:"result"`
+					Error  *dcrjson.RPCError `json:"error"`
+					ID     int               `json:"id"`
+				}
+				if err := json.Unmarshal(raw, &resp); err != nil {
+					continue
+				}
+				if resp.Error != nil {
+					lineErrs[resp.ID] = resp.Error
+				} else {
+					results[resp.ID] = resp.Result
+				}
+			}
+		}
+	} else {
+		for i, ln := range lines {
+			if ln.err != nil {
+				continue
+			}
+			params, err := cmdParams(ln.cmd)
+			if err != nil {
+				lineErrs[i] = err
+				continue
+			}
+			var result json.RawMessage
+			if err := client.Call(ctx, ln.method, &result, params...); err != nil {
+				lineErrs[i] = err
+				continue
+			}
+			results[i] = result
+		}
+	}
+
+	var failed bool
+	for i, ln := range lines {
+		if ln.err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", ln.text, ln.err)
+			failed = true
+			continue
+		}
+		if err, ok := lineErrs[i]; ok {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", ln.text, err)
+			failed = true
+			continue
+		}
+		var err error
+		if format != nil {
+			err = format.Display(results[i])
+		} else {
+			err = displayResult(results[i])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", ln.text, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("one or more batch commands failed")
+	}
+	return nil
+}
+
+// parseBatch reads and parses every non-blank line of in into a batchLine,
+// recording a per-line error for lines that don't name a usable command
+// rather than aborting the whole batch.  A read error from in stops reading
+// and is recorded as the error of one last line.  Lines are read with
+// bufio.Reader.ReadString, which has no line length limit, instead of
+// bufio.Scanner, which is capped at a 64KiB token by default and would
+// otherwise abort the whole batch on one oversized line, such as a
+// submitblock carrying a full block's hex payload.
+func parseBatch(cfg *config, in io.Reader) ([]batchLine, error) {
+	var lines []batchLine
+	r := bufio.NewReader(in)
+	for {
+		raw, readErr := r.ReadString('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			lines = append(lines, batchLine{text: raw, err: readErr})
+			break
+		}
+
+		text := strings.TrimSpace(raw)
+		if text == "" {
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			continue
+		}
+		fields := strings.Fields(text)
+		ln := batchLine{text: text, method: fields[0]}
+
+		var method interface{} = dcrdtypes.Method(ln.method)
+		usageFlags, err := dcrjson.MethodUsageFlags(method)
+		if err != nil {
+			method = wallettypes.Method(ln.method)
+			usageFlags, err = dcrjson.MethodUsageFlags(method)
+		}
+		if err != nil {
+			ln.err = fmt.Errorf("unrecognized command %q", ln.method)
+			lines = append(lines, ln)
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			continue
+		}
+		if usageFlags&unusableFlags(cfg) != 0 {
+			ln.err = fmt.Errorf("the '%s' command is unusable", method)
+			lines = append(lines, ln)
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			continue
+		}
+
+		params := make([]interface{}, len(fields[1:]))
+		for i, p := range fields[1:] {
+			params[i] = p
+		}
+		cmd, err := dcrjson.NewCmd(method, params...)
+		if err != nil {
+			ln.err = err
+		} else {
+			ln.cmd = cmd
+		}
+		lines = append(lines, ln)
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+	}
+	return lines, nil
+}
@@ -6,8 +6,10 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/url"
 	"os"
@@ -15,6 +17,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/decred/dcrd/dcrjson/v3"
 	"github.com/decred/dcrd/dcrutil/v3"
@@ -24,12 +27,18 @@ import (
 	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v2"
 )
 
-const (
-	// unusableFlags are the command usage flags which this utility are not
-	// able to use.  In particular it doesn't support websockets and
-	// consequently notifications.
-	unusableFlags = dcrjson.UFWebsocketOnly | dcrjson.UFNotification
-)
+// unusableFlags returns the command usage flags cfg's invocation of this
+// utility is not able to use.  Ordinarily that's every websocket-only or
+// notification command, since requests are sent one at a time and responses
+// are read back synchronously.  -subscribe mode exists specifically to use
+// those commands, so it drops UFWebsocketOnly from the mask.
+func unusableFlags(cfg *config) dcrjson.UsageFlag {
+	flags := dcrjson.UFWebsocketOnly | dcrjson.UFNotification
+	if cfg.Subscribe {
+		flags &^= dcrjson.UFWebsocketOnly
+	}
+	return flags
+}
 
 // Authorization types.
 const (
@@ -52,7 +61,7 @@ var (
 
 // listCommands categorizes and lists all of the usable commands along with
 // their one-line usage.
-func listCommands() {
+func listCommands(cfg *config) {
 	var categories = []struct {
 		Header string
 		Method interface{}
@@ -84,7 +93,7 @@ func listCommands() {
 			}
 
 			// Skip the commands that aren't usable from this utility.
-			if flags&unusableFlags != 0 {
+			if flags&unusableFlags(cfg) != 0 {
 				continue
 			}
 
@@ -113,22 +122,37 @@ func listCommands() {
 //
 // See loadConfig for details on the configuration load process.
 type config struct {
-	Config       flag.Value
-	ShowVersion  bool
-	ListCommands bool
-	RPCServer    string
-	Wallet       bool
-	TestNet      bool
-	SimNet       bool
-	RPCUser      string
-	RPCPassword  string
-	RPCCert      string
-	Proxy        string
-	ProxyUser    string
-	ProxyPass    string
-	AuthType     string
-	ClientCert   string
-	ClientKey    string
+	Config           flag.Value
+	ShowVersion      bool
+	ListCommands     bool
+	RPCServer        string
+	Wallet           bool
+	TestNet          bool
+	SimNet           bool
+	RPCUser          string
+	RPCPassword      string
+	RPCCert          string
+	Proxy            string
+	ProxyUser        string
+	ProxyPass        string
+	AuthType         string
+	ClientCert       string
+	ClientKey        string
+	HTTPMode         bool
+	NoTLS            bool
+	TLSSkipVerify    bool
+	Batch            bool
+	Subscribe        bool
+	SubscribeTimeout time.Duration
+	Profile          string
+	Format           string
+	FormatFile       string
+	Raw              bool
+	Compact          bool
+	RPCPassFile      string
+	RPCPassStdin     bool
+	ProxyPassFile    string
+	ProxyPassStdin   bool
 }
 
 func usage() {
@@ -166,6 +190,51 @@ Flags:
         SOCKS5 proxy password
   -proxyuser string
         SOCKS5 proxy username
+  -http
+        use a single HTTP/1 POST request instead of websockets
+  -notls
+        disable TLS and connect in the clear; default scheme becomes
+        "ws://" and no certificate is loaded
+  -skipverify
+        skip verification of the server's TLS certificate chain
+  -batch
+        read newline-delimited "method arg..." command lines from the file
+        argument (or stdin when none is given) and send them as a single
+        JSON-RPC batch request; only -http performs this as one round trip,
+        since the websocket and agent callers support just one request per
+        call, so without -http each line is still sent serially
+  -subscribe
+        allow websocket-only notification commands and print each
+        notification received as one JSON object per line until SIGINT
+  -subscribe-timeout duration
+        stop an -subscribe session after duration elapses (e.g. "30s")
+  -profile string
+        config file section to use; defaults to a name implied by
+        -wallet/-testnet (e.g. "testnet-wallet") when not given
+  -format template
+        execute a Go text/template against the result instead of the
+        default display
+  -format-file path
+        read the -format template from a file
+  -raw
+        emit the result's raw JSON unmodified
+  -compact
+        emit the result as single-line JSON
+  -rpcpass-file path
+        read the RPC password from the first line of path
+  -rpcpass-stdin
+        prompt for the RPC password (or read one line from stdin when not
+        a terminal)
+  -proxypass-file path
+        read the SOCKS5 proxy password from the first line of path
+  -proxypass-stdin
+        prompt for the SOCKS5 proxy password (or read one line from stdin
+        when not a terminal)
+
+Environment:
+  DCRCTL_RPCPASS    RPC password; used when -rpcpass-file is not given
+  DCRCTL_PROXYPASS  SOCKS5 proxy password; used when -proxypass-file is not
+                    given
 `)
 	os.Exit(2)
 }
@@ -193,6 +262,21 @@ func (c *config) FlagSet() *flag.FlagSet {
 	fs.StringVar(&c.AuthType, "authtype", "", "")
 	fs.StringVar(&c.ClientCert, "clientcert", "", "")
 	fs.StringVar(&c.ClientKey, "clientkey", "", "")
+	fs.BoolVar(&c.HTTPMode, "http", false, "")
+	fs.BoolVar(&c.NoTLS, "notls", false, "")
+	fs.BoolVar(&c.TLSSkipVerify, "skipverify", false, "")
+	fs.BoolVar(&c.Batch, "batch", false, "")
+	fs.BoolVar(&c.Subscribe, "subscribe", false, "")
+	fs.DurationVar(&c.SubscribeTimeout, "subscribe-timeout", 0, "")
+	fs.StringVar(&c.Profile, "profile", "", "")
+	fs.StringVar(&c.Format, "format", "", "")
+	fs.StringVar(&c.FormatFile, "format-file", "", "")
+	fs.BoolVar(&c.Raw, "raw", false, "")
+	fs.BoolVar(&c.Compact, "compact", false, "")
+	fs.StringVar(&c.RPCPassFile, "rpcpass-file", "", "")
+	fs.BoolVar(&c.RPCPassStdin, "rpcpass-stdin", false, "")
+	fs.StringVar(&c.ProxyPassFile, "proxypass-file", "", "")
+	fs.BoolVar(&c.ProxyPassStdin, "proxypass-stdin", false, "")
 	fs.Usage = usage
 	return fs
 }
@@ -261,6 +345,101 @@ func fileExists(name string) bool {
 	return true
 }
 
+// impliedProfile returns the config file section implied by the -wallet and
+// -testnet flags when -profile was not given explicitly, so users who
+// always pass the same network/wallet flags don't also need -profile.
+func impliedProfile(wallet, testnet bool) string {
+	switch {
+	case wallet && testnet:
+		return "testnet-wallet"
+	case wallet:
+		return "wallet"
+	case testnet:
+		return "testnet"
+	}
+	return ""
+}
+
+// scanProfileFlags does a lightweight scan of the raw command line
+// (bypassing flag parsing, which happens later) for the -profile, -wallet,
+// and -testnet flags, since the active config file section must be known
+// before the file is handed to flagfile.Parser.
+func scanProfileFlags(args []string) (profile string, wallet, testnet bool) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-profile" || a == "--profile":
+			if i+1 < len(args) {
+				profile = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(a, "-profile="):
+			profile = strings.TrimPrefix(a, "-profile=")
+		case strings.HasPrefix(a, "--profile="):
+			profile = strings.TrimPrefix(a, "--profile=")
+		case a == "-wallet" || a == "--wallet":
+			wallet = true
+		case a == "-testnet" || a == "--testnet":
+			testnet = true
+		}
+	}
+	return profile, wallet, testnet
+}
+
+// filterConfigSections reads the config file at path and returns the path of
+// a file containing only the unsectioned (global) keys plus the keys under
+// the "[activeSection]" header, with every other section stripped out
+// before the result is handed to flagfile.Parser.  Relative order of the
+// filtered lines is preserved, and flagfile.Parser applies them in order
+// with later occurrences of a key overriding earlier ones, so keys inside
+// activeSection only override the shared/global keys outside any section
+// when the conventional layout is followed -- global defaults written
+// before the sections that customize them.  If the file has no section
+// headers at all, path is returned unchanged and cleanup is a no-op, so
+// existing flat config files keep working exactly as before.
+func filterConfigSections(path, activeSection string) (filtered string, cleanup func(), err error) {
+	noop := func() {}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", noop, err
+	}
+
+	var out strings.Builder
+	section := ""
+	sectioned := false
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			sectioned = true
+			continue
+		}
+		if section == "" || section == activeSection {
+			out.WriteString(line)
+			out.WriteByte('\n')
+		}
+	}
+	if !sectioned {
+		return path, noop, nil
+	}
+
+	f, err := ioutil.TempFile("", "dcrctl-conf-")
+	if err != nil {
+		return "", noop, err
+	}
+	if _, err := f.WriteString(out.String()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", noop, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", noop, err
+	}
+	return f.Name(), func() { os.Remove(f.Name()) }, nil
+}
+
 // loadConfig initializes and parses the config using a config file and command
 // line options.
 //
@@ -299,12 +478,30 @@ func loadConfig() (*config, []string, error) {
 	} else if fileExists(defaultConfigFile) {
 		configPath = defaultConfigFile
 	}
+	// cleanup removes the temp file written by filterConfigSections, if any.
+	// It must be called explicitly before every os.Exit below since deferred
+	// functions do not run when os.Exit terminates the process.
+	cleanup := func() {}
 	if configPath != "" {
-		err := cfg.Config.Set(configPath)
+		profile, wallet, testnet := scanProfileFlags(os.Args[1:])
+		if profile == "" {
+			profile = impliedProfile(wallet, testnet)
+		}
+		var filtered string
+		var err error
+		filtered, cleanup, err = filterConfigSections(configPath, profile)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
+		defer cleanup()
+
+		err = cfg.Config.Set(filtered)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			cleanup()
+			os.Exit(1)
+		}
 	}
 	fs.Parse(args)
 
@@ -314,16 +511,25 @@ func loadConfig() (*config, []string, error) {
 	if cfg.ShowVersion {
 		fmt.Printf("%s version %s (Go version %s %s/%s)\n", appName,
 			versionString(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		cleanup()
 		os.Exit(0)
 	}
 
 	// Show the available commands and exit if the associated flag was
 	// specified.
 	if cfg.ListCommands {
-		listCommands()
+		listCommands(cfg)
+		cleanup()
 		os.Exit(0)
 	}
 
+	// Resolve the RPC and proxy passwords from the more secure alternatives
+	// to -P/-proxypass, if any were given.
+	if err := resolvePasswords(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
 	// Multiple networks can't be selected simultaneously.
 	numNets := 0
 	if cfg.TestNet {
@@ -340,9 +546,52 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// -notls and clientcert authentication are mutually exclusive since
+	// clientcert auth requires a TLS connection.
+	if cfg.NoTLS && cfg.AuthType == authTypeClientCert {
+		err := errors.New("loadConfig: -notls cannot be used with " +
+			"clientcert authentication")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// -subscribe requires a persistent websocket connection to receive
+	// notifications over, which -http does not provide.
+	if cfg.Subscribe && cfg.HTTPMode {
+		err := errors.New("loadConfig: -subscribe cannot be used with -http")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// -batch sends its commands and exits as soon as their results arrive,
+	// so a -subscribe command registered in the batch would have its
+	// notifications discarded the instant the connection is torn down.
+	if cfg.Subscribe && cfg.Batch {
+		err := errors.New("loadConfig: -subscribe cannot be used with -batch")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
+	// -format, -format-file, -raw, and -compact each select a different
+	// result display mode, so only one of them can be used at a time.
+	displayModes := 0
+	for _, set := range []bool{cfg.Format != "", cfg.FormatFile != "", cfg.Raw, cfg.Compact} {
+		if set {
+			displayModes++
+		}
+	}
+	if displayModes > 1 {
+		err := errors.New("loadConfig: -format, -format-file, -raw, and " +
+			"-compact are mutually exclusive")
+		fmt.Fprintln(os.Stderr, err)
+		return nil, nil, err
+	}
+
 	// Override the RPC certificate if the --wallet flag was specified and
-	// the user did not specify one.
+	// the user did not specify one.  -notls connections never load a
+	// certificate.
 	switch {
+	case cfg.NoTLS:
 	case cfg.Wallet && cfg.RPCCert == "" && fileExists(defaultWalletCertFile):
 		cfg.RPCCert = defaultWalletCertFile
 	case cfg.RPCCert == "" && fileExists(defaultRPCCertFile):
@@ -384,6 +633,11 @@ func normalizeServer(cfg *config) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	// -notls rewrites a wss scheme, the default for -s, to ws; any other
+	// scheme given by -s is kept as it is.
+	if cfg.NoTLS && parsed.Scheme == "wss" {
+		parsed.Scheme = "ws"
+	}
 	_, _, err = net.SplitHostPort(parsed.Host)
 	if err != nil {
 		port := defaultPort(cfg)
@@ -6,7 +6,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
@@ -19,6 +18,7 @@ import (
 	wallettypes "decred.org/dcrwallet/rpc/jsonrpc/types"
 	"github.com/decred/dcrd/dcrjson/v3"
 	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v2"
+	"github.com/jrick/wsrpc/v2"
 	"github.com/jrick/wsrpc/v2/agent"
 )
 
@@ -27,6 +27,91 @@ const (
 	listCmdMessage  = "Specify -l to list available commands"
 )
 
+// caller is satisfied by any client capable of performing a JSON-RPC method
+// call, whether over a websocket, a single HTTP/1 request, or the
+// background agent process.
+type caller interface {
+	Call(ctx context.Context, method string, result interface{}, args ...interface{}) error
+}
+
+// newCaller selects and constructs the caller used to perform RPC calls. It
+// prefers the background agent process when it is available and the
+// configured transport permits it, and otherwise dials the server directly.
+func newCaller(ctx context.Context, cfg *config) (caller, error) {
+	// proxy, -http, and -subscribe aren't supported by the agent: the agent
+	// has no notifier support, so -subscribe must always dial a websocket
+	// client directly to have its notifier installed.
+	if cfg.Proxy == "" && !cfg.HTTPMode && !cfg.Subscribe && agent.EnvironmentSet() {
+		ag := &agent.Client{
+			Address: cfg.RPCServer,
+			User:    cfg.RPCUser,
+			Pass:    cfg.RPCPassword,
+		}
+		if cfg.RPCCert != "" {
+			pem, err := ioutil.ReadFile(cfg.RPCCert)
+			if err != nil {
+				return nil, err
+			}
+			ag.RootCert = string(pem)
+		}
+		return ag, nil
+	}
+	return dialClient(ctx, cfg)
+}
+
+// cmdParams marshals cmd into a JSON-RPC request object and extracts its
+// already-marshalled parameters, which is the only way to recover the
+// concrete argument values dcrjson's command parsing produced so they can be
+// passed along to a caller's Call method.
+func cmdParams(cmd interface{}) ([]interface{}, error) {
+	marshalledJSON, err := dcrjson.MarshalCmd("1.0", 1, cmd)
+	if err != nil {
+		return nil, err
+	}
+	var requestObject struct {
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(marshalledJSON, &requestObject); err != nil {
+		return nil, err
+	}
+	params := make([]interface{}, len(requestObject.Params))
+	for i := range params {
+		params[i] = requestObject.Params[i]
+	}
+	return params, nil
+}
+
+// displayResult prints an RPC result the same way regardless of which
+// command produced it: strings are printed unquoted, objects and arrays are
+// pretty-printed, and anything else (numbers, booleans, null) is printed
+// as-is.
+func displayResult(result json.RawMessage) error {
+	if len(result) == 0 {
+		return nil
+	}
+
+	if result[0] == '"' {
+		var str string
+		if err := json.Unmarshal(result, &str); err != nil {
+			return fmt.Errorf("failed to unmarshal result: %v", err)
+		}
+		fmt.Println(str)
+		return nil
+	}
+
+	if result[0] == '{' || result[0] == '[' {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return fmt.Errorf("failed to format result: %v", err)
+		}
+		return nil
+	}
+
+	fmt.Printf("%s\n", result)
+	return nil
+}
+
 // commandUsage display the usage for a specific command.
 func commandUsage(method interface{}) {
 	usage, err := dcrjson.MethodUsageText(method)
@@ -47,6 +132,37 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := context.Background()
+
+	format, err := newResultFormatter(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if cfg.Batch {
+		in := io.Reader(stdin)
+		if len(args) > 0 {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			in = f
+		}
+		client, err := newCaller(ctx, cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := runBatch(ctx, cfg, client, in, format); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if len(args) < 1 {
 		fmt.Fprintln(os.Stderr, "missing command parameter")
 		usage()
@@ -66,7 +182,7 @@ func main() {
 		fmt.Fprintln(os.Stderr, listCmdMessage)
 		os.Exit(1)
 	}
-	if usageFlags&unusableFlags != 0 {
+	if usageFlags&unusableFlags(cfg) != 0 {
 		fmt.Fprintf(os.Stderr, "The '%s' command is unusable\n", method)
 		os.Exit(1)
 	}
@@ -78,11 +194,10 @@ func main() {
 	// too large for the Operating System to allow as a normal command line
 	// parameter, support using '-' as an argument to allow the argument
 	// to be read from a stdin pipe.
-	bio := bufio.NewReader(os.Stdin)
 	params := make([]interface{}, 0, len(args[1:]))
 	for _, arg := range args[1:] {
 		if arg == "-" {
-			param, err := bio.ReadString('\n')
+			param, err := stdin.ReadString('\n')
 			if err != nil && !errors.Is(err, io.EOF) {
 				fmt.Fprintf(os.Stderr, "Failed to read data "+
 					"from stdin: %v\n", err)
@@ -101,12 +216,6 @@ func main() {
 		params = append(params, arg)
 	}
 
-	// The only way to use dcrjson's argument parsing features is to create
-	// the concrete command type boxed in an interface{}, and then marshal
-	// this to a complete JSON-RPC request object.  So we do this, and then
-	// immediately unmarsal the parameters contained within so they can be
-	// passed to a far saner Call method.
-
 	// Attempt to create the appropriate command using the arguments
 	// provided by the user.
 	cmd, err := dcrjson.NewCmd(method, params...)
@@ -131,92 +240,51 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Marshal the command into a JSON-RPC byte slice in preparation for
-	// sending it to the RPC server.
-	marshalledJSON, err := dcrjson.MarshalCmd("1.0", 1, cmd)
+	// The only way to use dcrjson's argument parsing features is to create
+	// the concrete command type boxed in an interface{}, and then marshal
+	// this to a complete JSON-RPC request object.  So we do this, and then
+	// immediately unmarshal the parameters contained within so they can be
+	// passed to a far saner Call method.
+	callParams, err := cmdParams(cmd)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	// Now the parameters are actually available.
-	var requestObject struct {
-		Params []json.RawMessage `json:"params"`
-	}
-	err = json.Unmarshal(marshalledJSON, &requestObject)
+	// client is the caller used to perform the call.  This is either a newly
+	// dialed wsrpc.Client, an httpClient when -http was specified, or a
+	// connection to the agent process.
+	client, err := newCaller(ctx, cfg)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	callParams := make([]interface{}, len(requestObject.Params))
-	for i := range callParams {
-		callParams[i] = requestObject.Params[i]
-	}
-
-	// Caller is the client to perform the call.  This is either a newly
-	// dialed wsrpc.Client, or (later) a connection to the agent process.
-	var caller interface {
-		Call(ctx context.Context, method string, result interface{}, args ...interface{}) error
-	}
 
-	ctx := context.Background()
 	var result json.RawMessage
-	// proxy isn't supported by the agent
-	if cfg.Proxy == "" && agent.EnvironmentSet() {
-		ag := &agent.Client{
-			Address: cfg.RPCServer,
-			User:    cfg.RPCUser,
-			Pass:    cfg.RPCPassword,
-		}
-		if cfg.RPCCert != "" {
-			pem, err := ioutil.ReadFile(cfg.RPCCert)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, err)
-				os.Exit(1)
-			}
-			ag.RootCert = string(pem)
-		}
-		caller = ag
-	} else {
-		caller, err = dialClient(ctx, cfg)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
-		}
-	}
-	err = caller.Call(ctx, methodStr, &result, callParams...)
+	err = client.Call(ctx, methodStr, &result, callParams...)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	if len(result) == 0 {
-		return
+	if format != nil {
+		err = format.Display(result)
+	} else {
+		err = displayResult(result)
 	}
-
-	// Choose how to display the result based on its type.
-	if result[0] == '"' {
-		var str string
-		if err := json.Unmarshal(result, &str); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to unmarshal result: %v",
-				err)
-			os.Exit(1)
-		}
-		fmt.Println(str)
-		return
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	if result[0] == '{' || result[0] == '[' {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		err := enc.Encode(result)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to format result: %v",
-				err)
-			os.Exit(1)
+	// -subscribe mode stays connected printing notifications (installed on
+	// the client by newCaller/dialClient) until interrupted, timed out, or
+	// the connection closes.
+	if cfg.Subscribe {
+		var done <-chan struct{}
+		if wc, ok := client.(*wsrpc.Client); ok {
+			done = wc.Done()
 		}
-		return
+		waitForNotifications(done, cfg.SubscribeTimeout)
 	}
-
-	fmt.Printf("%s\n", result)
 }
@@ -0,0 +1,84 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Copyright (c) 2015-2020 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/template"
+)
+
+// resultFormatter overrides the default display logic in displayResult with
+// one of the -format, -raw, or -compact modes.
+type resultFormatter struct {
+	tmpl    *template.Template
+	raw     bool
+	compact bool
+}
+
+// newResultFormatter builds a resultFormatter from the -format, -format-file,
+// -raw, and -compact settings in cfg.  It returns a nil formatter when none
+// of those were specified, meaning the caller should fall back to
+// displayResult.
+func newResultFormatter(cfg *config) (*resultFormatter, error) {
+	switch {
+	case cfg.Format != "" || cfg.FormatFile != "":
+		text := cfg.Format
+		if cfg.FormatFile != "" {
+			b, err := ioutil.ReadFile(cfg.FormatFile)
+			if err != nil {
+				return nil, err
+			}
+			text = string(b)
+		}
+		tmpl, err := template.New("format").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -format template: %v", err)
+		}
+		return &resultFormatter{tmpl: tmpl}, nil
+
+	case cfg.Raw:
+		return &resultFormatter{raw: true}, nil
+
+	case cfg.Compact:
+		return &resultFormatter{compact: true}, nil
+	}
+	return nil, nil
+}
+
+// Display writes result to stdout according to the formatter's mode.
+func (f *resultFormatter) Display(result json.RawMessage) error {
+	if len(result) == 0 {
+		return nil
+	}
+
+	switch {
+	case f.tmpl != nil:
+		var v interface{}
+		if err := json.Unmarshal(result, &v); err != nil {
+			return fmt.Errorf("failed to unmarshal result: %v", err)
+		}
+		return f.tmpl.Execute(os.Stdout, v)
+
+	case f.raw:
+		if _, err := os.Stdout.Write(result); err != nil {
+			return err
+		}
+		fmt.Println()
+		return nil
+
+	default: // f.compact
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, result); err != nil {
+			return fmt.Errorf("failed to format result: %v", err)
+		}
+		fmt.Println(buf.String())
+		return nil
+	}
+}
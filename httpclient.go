@@ -6,21 +6,37 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/http"
+	"net/url"
 
+	"github.com/decred/dcrd/dcrjson/v3"
 	"github.com/decred/go-socks/socks"
 	"github.com/jrick/wsrpc/v2"
 )
 
-// dialClient dials and returns a websocket JSON-RPC client that is configured
-// according to the proxy TLS, and authentication settings in the application
-// config.
-func dialClient(ctx context.Context, cfg *config) (*wsrpc.Client, error) {
+// dialClient returns a caller configured according to the proxy, TLS, and
+// authentication settings in the application config.  Unless -http is
+// specified, the returned caller is a websocket client; otherwise it is an
+// httpClient that performs each call as a single HTTP/1 POST request.
+func dialClient(ctx context.Context, cfg *config) (caller, error) {
+	if cfg.HTTPMode {
+		return newHTTPClient(cfg)
+	}
+	return dialWSClient(ctx, cfg)
+}
+
+// dialWSClient dials and returns a websocket JSON-RPC client that is
+// configured according to the proxy, TLS, and authentication settings in the
+// application config.
+func dialWSClient(ctx context.Context, cfg *config) (*wsrpc.Client, error) {
 	var opts []wsrpc.Option
 
 	// Configure proxy if needed.
@@ -41,8 +57,46 @@ func dialClient(ctx context.Context, cfg *config) (*wsrpc.Client, error) {
 	}
 
 	// Configure TLS if needed.
+	tc, err := tlsConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tc != nil {
+		opts = append(opts, wsrpc.WithTLSConfig(tc))
+	}
+
+	// Configure auth.
+	user, pass := cfg.RPCUser, cfg.RPCPassword
+	if user != "" || pass != "" {
+		opts = append(opts, wsrpc.WithBasicAuth(user, pass))
+	}
+
+	// -subscribe mode installs a notifier that prints every notification
+	// received on the connection.
+	if cfg.Subscribe {
+		opts = append(opts, wsrpc.WithNotifier(notificationPrinter{}))
+	}
+
+	return wsrpc.Dial(ctx, cfg.RPCServer, opts...)
+}
+
+// tlsConfig builds the *tls.Config to authenticate the RPC server (and, for
+// clientcert authentication, the client) from the certificate paths and
+// transport settings in cfg.  It returns a nil config when -notls was
+// specified or no certificate or verification settings require one.
+func tlsConfig(cfg *config) (*tls.Config, error) {
+	if cfg.NoTLS {
+		return nil, nil
+	}
+	if cfg.RPCCert == "" && !cfg.TLSSkipVerify && cfg.AuthType != authTypeClientCert {
+		return nil, nil
+	}
+
+	tc := new(tls.Config)
+	if cfg.TLSSkipVerify {
+		tc.InsecureSkipVerify = true
+	}
 	if cfg.RPCCert != "" {
-		tc := new(tls.Config)
 		pem, err := ioutil.ReadFile(cfg.RPCCert)
 		if err != nil {
 			return nil, err
@@ -54,22 +108,159 @@ func dialClient(ctx context.Context, cfg *config) (*wsrpc.Client, error) {
 				cfg.RPCCert)
 		}
 		tc.RootCAs = pool
-		if cfg.AuthType == authTypeClientCert {
-			keypair, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
-			if err != nil {
-				return nil, fmt.Errorf("read client keypair: %v", err)
-			}
-			tc.Certificates = []tls.Certificate{keypair}
+	}
+	if cfg.AuthType == authTypeClientCert {
+		keypair, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("read client keypair: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{keypair}
+	}
+	return tc, nil
+}
 
+// httpClient is a caller that performs each JSON-RPC method call as its own
+// HTTP/1 POST request rather than over a persistent websocket connection.
+// This is useful against servers that sit behind reverse proxies or load
+// balancers that do not support the websocket upgrade, or that have their
+// websocket listener disabled entirely.
+type httpClient struct {
+	url    string
+	user   string
+	pass   string
+	client *http.Client
+}
+
+// newHTTPClient creates an httpClient configured according to the TLS and
+// authentication settings in the application config.
+func newHTTPClient(cfg *config) (*httpClient, error) {
+	u, err := httpURL(cfg.RPCServer)
+	if err != nil {
+		return nil, err
+	}
+
+	tc, err := tlsConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{TLSClientConfig: tc}
+
+	// Configure proxy if needed.
+	if cfg.Proxy != "" {
+		proxy := &socks.Proxy{
+			Addr:     cfg.Proxy,
+			Username: cfg.ProxyUser,
+			Password: cfg.ProxyPass,
 		}
-		opts = append(opts, wsrpc.WithTLSConfig(tc))
+		transport.DialContext = proxy.DialContext
 	}
 
-	// Configure auth.
-	user, pass := cfg.RPCUser, cfg.RPCPassword
-	if user != "" || pass != "" {
-		opts = append(opts, wsrpc.WithBasicAuth(user, pass))
+	return &httpClient{
+		url:    u,
+		user:   cfg.RPCUser,
+		pass:   cfg.RPCPassword,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+// httpURL translates the websocket RPC server URL (e.g. "wss://host:port/ws")
+// into the URL of the plain HTTP/1 JSON-RPC endpoint served at the same
+// address.
+func httpURL(server string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
 	}
+	u.Path = "/"
+	return u.String(), nil
+}
 
-	return wsrpc.Dial(ctx, cfg.RPCServer, opts...)
+// httpRequest is a JSON-RPC 1.0 request object.
+type httpRequest struct {
+	Jsonrpc string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// httpResponse is a JSON-RPC 1.0 response object.
+type httpResponse struct {
+	Result json.RawMessage   `json:"result"`
+	Error  *dcrjson.RPCError `json:"error"`
+	ID     uint64            `json:"id"`
+}
+
+// do POSTs payload to the HTTP/1 JSON-RPC endpoint and returns the raw
+// response body.
+func (c *httpClient) do(ctx context.Context, payload []byte) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.user != "" || c.pass != "" {
+		httpReq.SetBasicAuth(c.user, c.pass)
+	}
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Call implements the caller interface by marshaling method and args into a
+// JSON-RPC request object and POSTing it to the configured HTTP endpoint.
+func (c *httpClient) Call(ctx context.Context, method string, result interface{}, args ...interface{}) error {
+	req := &httpRequest{
+		Jsonrpc: "1.0",
+		ID:      1,
+		Method:  method,
+		Params:  args,
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	respBody, err := c.do(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	var rpcResp httpResponse
+	if err := json.Unmarshal(respBody, &rpcResp); err != nil {
+		return fmt.Errorf("unable to unmarshal response: %v", err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if result == nil || len(rpcResp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+// CallRaw implements the rawCaller interface by POSTing an already-marshalled
+// payload — typically a JSON-RPC 2.0 batch array — and returning each
+// response object's raw bytes as received from the server.
+func (c *httpClient) CallRaw(ctx context.Context, payload []byte) ([]json.RawMessage, error) {
+	respBody, err := c.do(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []json.RawMessage
+	if err := json.Unmarshal(respBody, &responses); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal batch response: %v", err)
+	}
+	return responses, nil
 }
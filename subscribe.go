@@ -0,0 +1,55 @@
+// Copyright (c) 2013-2015 The btcsuite developers
+// Copyright (c) 2015-2020 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// notificationPrinter is a wsrpc.Notifier that writes each notification
+// received on the connection as a single JSON object per line to stdout,
+// turning -subscribe mode into a usable tail-style monitor.
+type notificationPrinter struct{}
+
+// Notify implements wsrpc.Notifier.
+func (notificationPrinter) Notify(method string, params json.RawMessage) error {
+	obj := struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}{method, params}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to marshal notification:", err)
+		return nil
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// waitForNotifications blocks until SIGINT is received, done is closed (the
+// connection went away), or, if timeout is positive, timeout elapses.
+func waitForNotifications(done <-chan struct{}, timeout time.Duration) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	select {
+	case <-sigCh:
+	case <-timeoutCh:
+	case <-done:
+	}
+}